@@ -1,15 +1,21 @@
 package cloudngfw
 
 import (
+	"context"
+	"crypto/sha256"
 	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"log"
+	"math/rand"
+	"net"
 	"net/http"
 	"os"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
@@ -18,10 +24,32 @@ import (
 	"github.com/aws/aws-sdk-go/aws/signer/v4"
 	"github.com/aws/aws-sdk-go/service/sts"
 
+	awsv2 "github.com/aws/aws-sdk-go-v2/aws"
+	signerv2 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	configv2 "github.com/aws/aws-sdk-go-v2/config"
+	stsv2 "github.com/aws/aws-sdk-go-v2/service/sts"
+	stsv2types "github.com/aws/aws-sdk-go-v2/service/sts/types"
+
+	"golang.org/x/sync/singleflight"
+
 	"github.com/paloaltonetworks/cloud-ngfw-aws-go/api"
 	"github.com/paloaltonetworks/cloud-ngfw-aws-go/permissions"
 )
 
+// jwtLifetime is how long a JWT returned by getJwt (Expires: 90, in minutes)
+// remains valid. jwtRefreshWindow is how much of that lifetime must still
+// remain; once less than that is left, the JWT is refreshed proactively
+// rather than waiting for a 401/403.
+const (
+	jwtLifetime      = 90 * time.Minute
+	jwtRefreshWindow = jwtLifetime / 10
+)
+
+// errArnCombined is returned when Client.Arn (a single shared role ARN) is
+// configured alongside LfaArn/LraArn; they're mutually exclusive ways to
+// authorize JWTs.
+var errArnCombined = errors.New("Arn cannot be combined with LfaArn/LraArn; configure one or the other")
+
 // Client is the client.
 type Client struct {
 	Host      string            `json:"host"`
@@ -34,13 +62,32 @@ type Client struct {
 
 	LfaArn string `json:"lfa-arn"`
 	LraArn string `json:"lra-arn"`
-	Arn    string `json:"arn"`
+
+	// Arn is a single role ARN that's authorized for both firewall and
+	// rulestack admin JWTs. It's mutually exclusive with LfaArn/LraArn.
+	Arn string `json:"arn"`
 
 	CheckEnvironment bool `json:"-"`
 
 	SkipVerifyCertificate bool            `json:"skip-verify-certificate"`
 	Transport             *http.Transport `json:"-"`
 
+	// Retry settings for transient API failures. MaxRetries is the number
+	// of retries attempted after the initial request; leaving it unset (0)
+	// picks up the default of 3, same as Timeout defaulting to 20 below. To
+	// explicitly request zero retries, set MaxRetries to -1: send's attempt
+	// counter starts at 0, which already exceeds -1, so no retry is ever
+	// attempted. -1 is left as-is by initCon rather than normalized to 0, so
+	// that the choice survives initCon running more than once (NewClientV2
+	// calls it directly, then again via Initialize). Delay between attempts
+	// is min(MaxRetryDelay, MinRetryDelay * 2^attempt) plus uniform jitter in
+	// [0, delay/2]. Retryable, if set, overrides the default retry decision
+	// (net.Error timeouts, connection resets, HTTP 429, and 5xx responses).
+	MaxRetries    int                              `json:"max-retries"`
+	MinRetryDelay time.Duration                    `json:"-"`
+	MaxRetryDelay time.Duration                    `json:"-"`
+	Retryable     func(*http.Response, error) bool `json:"-"`
+
 	Logging               uint32   `json:"-"`
 	LoggingFromInitialize []string `json:"logging"`
 
@@ -53,6 +100,22 @@ type Client struct {
 	apiPrefix string
 	con       *http.Client
 
+	// cfgV2 is set by NewClientV2 and marks this Client as using
+	// aws-sdk-go-v2 for STS calls and SigV4 signing. Left nil for Clients
+	// built the v1 way, which continues to work during the deprecation
+	// window.
+	cfgV2 *awsv2.Config
+
+	// jwtMu guards FirewallJwt/RulestackJwt and their expiry times, since
+	// Communicate's auto-refresh can run from multiple goroutines.
+	jwtMu              sync.RWMutex
+	firewallJwtExpiry  time.Time
+	rulestackJwtExpiry time.Time
+
+	// jwtGroup collapses concurrent refreshes of the same JWT into a single
+	// in-flight AssumeRole + token exchange.
+	jwtGroup singleflight.Group
+
 	// Variables for testing.
 	testData        [][]byte
 	testErrors      []error
@@ -75,6 +138,50 @@ func (c *Client) Initialize() error {
 	return nil
 }
 
+// NewClientV2 configures c to authenticate with aws-sdk-go-v2 instead of
+// the legacy v1 path used by Initialize(), then calls Initialize() and
+// returns c. cfgOpts are passed through to config.LoadDefaultConfig, so
+// callers can supply their own aws.CredentialsProvider (e.g.
+// config.WithCredentialsProvider(...)) to reach IMDS, ECS container
+// credentials, web identity / IRSA, SSO, or any other v2 credential
+// source; with no options the default v2 credential chain is used. Once
+// built this way, RefreshJwts and Communicate's SigV4 signing are routed
+// through aws-sdk-go-v2 for the lifetime of c.
+//
+// c is taken and returned by pointer, not by value, since Client holds a
+// mutex and a singleflight.Group that must not be copied.
+//
+// Region is resolved through initCon's usual env var / JSON creds file /
+// explicit field precedence before it's handed to config.LoadDefaultConfig,
+// so a Region left unset on c still comes from CLOUD_NGFW_REGION or the
+// creds file the same way it does on the v1 path.
+//
+// The v1 constructor path (building a Client directly and calling
+// Initialize) keeps working during the v1->v2 deprecation window.
+func NewClientV2(ctx context.Context, c *Client, cfgOpts ...func(*configv2.LoadOptions) error) (*Client, error) {
+	// Resolve c.Region (env var / JSON creds file / explicit field) via the
+	// same precedence the v1 path uses before it's baked into cfgV2 below;
+	// Initialize() calls initCon() again afterward, which is a no-op for
+	// anything already resolved.
+	if err := c.initCon(); err != nil {
+		return nil, err
+	}
+
+	opts := append([]func(*configv2.LoadOptions) error{configv2.WithRegion(c.Region)}, cfgOpts...)
+
+	cfg, err := configv2.LoadDefaultConfig(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+	c.cfgV2 = &cfg
+
+	if err := c.Initialize(); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
 // Log logs an API action.
 func (c *Client) Log(method, msg string, i ...interface{}) {
 	switch method {
@@ -102,40 +209,44 @@ func (c *Client) Log(method, msg string, i ...interface{}) {
 }
 
 // RefreshJwts refreshes all JWTs and stores them for future API calls.
+//
+// This is a thin wrapper around RefreshJwtsWithContext using
+// context.Background(); callers that need cancellation or deadlines should
+// call RefreshJwtsWithContext directly.
 func (c *Client) RefreshJwts() error {
-	if c.Logging&LogLogin == LogLogin {
-		log.Printf("(login) refreshing JWTs...")
-	}
+	return c.RefreshJwtsWithContext(context.Background())
+}
 
-	jwtReq := getJwt{
-		Expires: 90,
-		KeyInfo: &jwtKeyInfo{
-			Region: c.Region,
-			Tenant: "XY",
-		},
+// RefreshJwtsWithContext is RefreshJwts with caller-supplied context.Context
+// propagation and cancellation. The context is passed into the underlying
+// STS AssumeRole call (v1's AssumeRoleWithContext or v2's AssumeRole, which
+// is already context-aware) and into the JWT retrieval request.
+func (c *Client) RefreshJwtsWithContext(ctx context.Context) error {
+	if c.Arn != "" && (c.LfaArn != "" || c.LraArn != "") {
+		return errArnCombined
 	}
 
-	var creds *credentials.Credentials
-	if c.AccessKey != "" || c.SecretKey != "" {
-		creds = credentials.NewStaticCredentials(c.AccessKey, c.SecretKey, "")
+	if c.Logging&LogLogin == LogLogin {
+		log.Printf("(login) refreshing JWTs...")
 	}
 
-	sess, err := session.NewSessionWithOptions(session.Options{
-		Config: aws.Config{
-			Credentials: creds,
-			Region:      aws.String(c.Region),
-		},
-	})
-
+	assume, err := c.assumeRoleFunc(ctx)
 	if err != nil {
 		return err
 	}
 
-	svc := sts.New(sess)
-
-	// Get a JWT that works for both firewall and rulestack admins.
+	// A single, shared role ARN gets both admin JWTs from one call.
 	if c.Arn != "" {
-		return fmt.Errorf("No endpoint yet known for shared ARN JWT retrieval")
+		if c.Logging&LogLogin == LogLogin {
+			log.Printf("(login) refreshing shared ARN JWTs...")
+		}
+		firewallJwt, rulestackJwt, err := c.fetchSharedJwtsWith(ctx, assume, c.Arn)
+		if err != nil {
+			return err
+		}
+		c.setJwt(permissions.Firewall, firewallJwt)
+		c.setJwt(permissions.Rulestack, rulestackJwt)
+		return nil
 	}
 
 	// Get a firewall JWT.
@@ -143,22 +254,11 @@ func (c *Client) RefreshJwts() error {
 		if c.Logging&LogLogin == LogLogin {
 			log.Printf("(login) refreshing firewall JWT...")
 		}
-		result, err := svc.AssumeRole(&sts.AssumeRoleInput{
-			RoleArn:         aws.String(c.LfaArn),
-			RoleSessionName: aws.String("sdk_session"),
-		})
-		if err != nil {
-			return err
-		}
-
-		var ans authResponse
-		_, err = c.Communicate(
-			"", http.MethodGet, []string{"v1", "mgmt", "tokens", "cloudfirewalladmin"}, jwtReq, &ans, result.Credentials)
+		jwt, err := c.fetchJwtWith(ctx, assume, c.LfaArn, "cloudfirewalladmin")
 		if err != nil {
 			return err
 		}
-
-		c.FirewallJwt = ans.Resp.Jwt
+		c.setJwt(permissions.Firewall, jwt)
 	}
 
 	// Get rulestack JWT.
@@ -166,27 +266,288 @@ func (c *Client) RefreshJwts() error {
 		if c.Logging&LogLogin == LogLogin {
 			log.Printf("(login) refreshing rulestack JWT...")
 		}
-		result, err := svc.AssumeRole(&sts.AssumeRoleInput{
-			RoleArn:         aws.String(c.LraArn),
+		jwt, err := c.fetchJwtWith(ctx, assume, c.LraArn, "cloudrulestackadmin")
+		if err != nil {
+			return err
+		}
+		c.setJwt(permissions.Rulestack, jwt)
+	}
+
+	return nil
+}
+
+// v1Session builds the v1 AWS session used for STS calls, honoring
+// c.AccessKey/c.SecretKey the same way as the initial RefreshJwtsWithContext
+// call so proactive/reactive refreshes assume the role under the same
+// identity the Client was configured with.
+func (c *Client) v1Session() (*session.Session, error) {
+	var creds *credentials.Credentials
+	if c.AccessKey != "" || c.SecretKey != "" {
+		creds = credentials.NewStaticCredentials(c.AccessKey, c.SecretKey, "")
+	}
+
+	return session.NewSessionWithOptions(session.Options{
+		Config: aws.Config{
+			Credentials: creds,
+			Region:      aws.String(c.Region),
+		},
+	})
+}
+
+// assumeRoleFunc assumes a role ARN and returns the resulting credentials in
+// the v1 shape CommunicateWithContext's SigV4 signing accepts, regardless of
+// which SDK generation actually performed the AssumeRole call.
+type assumeRoleFunc func(ctx context.Context, roleArn string) (*sts.Credentials, error)
+
+// assumeRoleFunc returns the AssumeRole step appropriate for c: aws-sdk-go-v2
+// when c was built with NewClientV2, aws-sdk-go v1 (honoring
+// AccessKey/SecretKey via v1Session) otherwise. Everything downstream of
+// AssumeRole -- building the JWT request, exchanging it, parsing the
+// response -- is identical for both SDK generations, so fetchJwtWith and
+// fetchSharedJwtsWith take this as a parameter instead of existing as
+// parallel v1/v2 copies.
+func (c *Client) assumeRoleFunc(ctx context.Context) (assumeRoleFunc, error) {
+	if c.cfgV2 != nil {
+		svc := stsv2.NewFromConfig(*c.cfgV2)
+		return func(ctx context.Context, roleArn string) (*sts.Credentials, error) {
+			result, err := svc.AssumeRole(ctx, &stsv2.AssumeRoleInput{
+				RoleArn:         awsv2.String(roleArn),
+				RoleSessionName: awsv2.String("sdk_session"),
+			})
+			if err != nil {
+				return nil, err
+			}
+			return v2CredsToV1(result.Credentials), nil
+		}, nil
+	}
+
+	sess, err := c.v1Session()
+	if err != nil {
+		return nil, err
+	}
+	svc := sts.New(sess)
+
+	return func(ctx context.Context, roleArn string) (*sts.Credentials, error) {
+		result, err := svc.AssumeRoleWithContext(ctx, &sts.AssumeRoleInput{
+			RoleArn:         aws.String(roleArn),
 			RoleSessionName: aws.String("sdk_session"),
 		})
 		if err != nil {
-			return err
+			return nil, err
 		}
+		return result.Credentials, nil
+	}, nil
+}
+
+// newJwtReq builds the body sent to every JWT retrieval endpoint.
+func (c *Client) newJwtReq() getJwt {
+	return getJwt{
+		Expires: 90,
+		KeyInfo: &jwtKeyInfo{
+			Region: c.Region,
+			Tenant: "XY",
+		},
+	}
+}
 
-		var ans authResponse
-		_, err = c.Communicate(
-			"", http.MethodGet, []string{"v1", "mgmt", "tokens", "cloudrulestackadmin"}, jwtReq, &ans, result.Credentials)
+// fetchJwtWith assumes roleArn via assume and exchanges the resulting
+// credentials for a JWT from the given token endpoint.
+func (c *Client) fetchJwtWith(ctx context.Context, assume assumeRoleFunc, roleArn, tokenPath string) (string, error) {
+	creds, err := assume(ctx, roleArn)
+	if err != nil {
+		return "", err
+	}
+
+	jwtReq := c.newJwtReq()
+
+	var ans authResponse
+	_, err = c.CommunicateWithContext(
+		ctx, "", http.MethodGet, []string{"v1", "mgmt", "tokens", tokenPath}, jwtReq, &ans, creds)
+	if err != nil {
+		return "", err
+	}
+
+	return ans.Resp.Jwt, nil
+}
+
+// fetchSharedJwtsWith assumes roleArn via assume and exchanges the resulting
+// credentials for both admin JWTs from the combined token endpoint, used
+// when Client.Arn (a single, shared role ARN) is configured.
+func (c *Client) fetchSharedJwtsWith(ctx context.Context, assume assumeRoleFunc, roleArn string) (firewallJwt, rulestackJwt string, err error) {
+	creds, err := assume(ctx, roleArn)
+	if err != nil {
+		return "", "", err
+	}
+
+	jwtReq := c.newJwtReq()
+
+	var ans sharedAuthResponse
+	_, err = c.CommunicateWithContext(
+		ctx, "", http.MethodGet, []string{"v1", "mgmt", "tokens", "cloudngfwadmin"}, jwtReq, &ans, creds)
+	if err != nil {
+		return "", "", err
+	}
+
+	return ans.Resp.FirewallJwt, ans.Resp.RulestackJwt, nil
+}
+
+// sharedAuthResponse is the JSON response from the combined
+// v1/mgmt/tokens/cloudngfwadmin endpoint used when Client.Arn is set: a
+// single AssumeRole exchange yields both the firewall and rulestack JWTs.
+type sharedAuthResponse struct {
+	Resp struct {
+		FirewallJwt  string `json:"firewall-jwt"`
+		RulestackJwt string `json:"rulestack-jwt"`
+	} `json:"response"`
+}
+
+// Ok satisfies api.Oker.
+func (r *sharedAuthResponse) Ok() bool {
+	return r.Resp.FirewallJwt != "" && r.Resp.RulestackJwt != ""
+}
+
+// Error satisfies api.Oker / error.
+func (r *sharedAuthResponse) Error() string {
+	return "failed to retrieve shared ARN JWTs"
+}
+
+// setJwt stores the JWT for auth and starts its lifetime clock.
+func (c *Client) setJwt(auth, jwt string) {
+	c.jwtMu.Lock()
+	defer c.jwtMu.Unlock()
+
+	switch auth {
+	case permissions.Firewall:
+		c.FirewallJwt = jwt
+		c.firewallJwtExpiry = time.Now().Add(jwtLifetime)
+	case permissions.Rulestack:
+		c.RulestackJwt = jwt
+		c.rulestackJwtExpiry = time.Now().Add(jwtLifetime)
+	}
+}
+
+// getJwt returns the current JWT for auth.
+func (c *Client) getJwt(auth string) string {
+	c.jwtMu.RLock()
+	defer c.jwtMu.RUnlock()
+
+	switch auth {
+	case permissions.Firewall:
+		return c.FirewallJwt
+	case permissions.Rulestack:
+		return c.RulestackJwt
+	default:
+		return ""
+	}
+}
+
+// jwtNeedsRefresh reports whether auth's JWT is missing or has less than
+// jwtRefreshWindow left before it expires.
+func (c *Client) jwtNeedsRefresh(auth string) bool {
+	c.jwtMu.RLock()
+	defer c.jwtMu.RUnlock()
+
+	switch auth {
+	case permissions.Firewall:
+		return c.FirewallJwt == "" || time.Until(c.firewallJwtExpiry) < jwtRefreshWindow
+	case permissions.Rulestack:
+		return c.RulestackJwt == "" || time.Until(c.rulestackJwtExpiry) < jwtRefreshWindow
+	default:
+		return false
+	}
+}
+
+// ensureJwt proactively refreshes auth's JWT if it's missing or close to
+// expiring, so a long-running caller doesn't have to wait for a 401/403 to
+// discover the token lapsed.
+func (c *Client) ensureJwt(ctx context.Context, auth string) error {
+	if !c.jwtNeedsRefresh(auth) {
+		return nil
+	}
+
+	return c.refreshAuthJwt(ctx, auth, false)
+}
+
+// refreshAuthJwt refreshes the JWT for a single admin type, collapsing
+// concurrent callers for the same auth into one AssumeRole + token exchange.
+//
+// force skips the jwtNeedsRefresh re-check inside the singleflight callback.
+// The proactive path (ensureJwt) should pass false, since our clock-based
+// expiry estimate is what decided a refresh was needed in the first place.
+// The reactive 401/403 path must pass true: the server has already told us
+// the current JWT is bad regardless of what our local expiry estimate says
+// (clock skew, server-side revocation, early invalidation), so running the
+// re-check there would let a concurrent refresh "satisfy" our retry with
+// the very JWT that was just rejected.
+func (c *Client) refreshAuthJwt(ctx context.Context, auth string, force bool) error {
+	// In shared-ARN mode, a firewall and a rulestack refresh both resolve to
+	// the same combined AssumeRole + token exchange, so they share a key and
+	// only one of them actually hits the network.
+	key := auth
+	if c.Arn != "" {
+		key = "shared"
+	}
+
+	_, err, _ := c.jwtGroup.Do(key, func() (interface{}, error) {
+		// Re-check: another goroutine may have already refreshed this JWT
+		// while we were waiting to enter the singleflight group.
+		if !force && !c.jwtNeedsRefresh(auth) {
+			return nil, nil
+		}
+
+		return nil, c.refreshJwtFor(ctx, auth)
+	})
+
+	return err
+}
+
+// refreshJwtFor refreshes the JWT for a single admin type (permissions.Firewall
+// or permissions.Rulestack), routing through aws-sdk-go-v2 when c.cfgV2 is set.
+func (c *Client) refreshJwtFor(ctx context.Context, auth string) error {
+	assume, err := c.assumeRoleFunc(ctx)
+	if err != nil {
+		return err
+	}
+
+	if c.Arn != "" {
+		firewallJwt, rulestackJwt, err := c.fetchSharedJwtsWith(ctx, assume, c.Arn)
 		if err != nil {
 			return err
 		}
 
-		c.RulestackJwt = ans.Resp.Jwt
+		c.setJwt(permissions.Firewall, firewallJwt)
+		c.setJwt(permissions.Rulestack, rulestackJwt)
+		return nil
 	}
 
+	roleArn, tokenPath := c.LfaArn, "cloudfirewalladmin"
+	if auth == permissions.Rulestack {
+		roleArn, tokenPath = c.LraArn, "cloudrulestackadmin"
+	}
+	if roleArn == "" {
+		return fmt.Errorf("No role ARN configured for auth type %q", auth)
+	}
+
+	jwt, err := c.fetchJwtWith(ctx, assume, roleArn, tokenPath)
+	if err != nil {
+		return err
+	}
+
+	c.setJwt(auth, jwt)
 	return nil
 }
 
+// v2CredsToV1 adapts aws-sdk-go-v2 STS credentials to the aws-sdk-go v1
+// shape Communicate accepts, so the same signing plumbing can be reused
+// regardless of which SDK generation obtained the credentials.
+func v2CredsToV1(creds *stsv2types.Credentials) *sts.Credentials {
+	return &sts.Credentials{
+		AccessKeyId:     creds.AccessKeyId,
+		SecretAccessKey: creds.SecretAccessKey,
+		SessionToken:    creds.SessionToken,
+	}
+}
+
 /*
 Communicate sends information to the API.
 
@@ -205,8 +566,26 @@ Param creds is only used internally for refreshing the JWTs and can otherwise be
 
 This function returns the content of the body from the API call and any errors that
 may have been present.
+
+Transient failures (net.Error timeouts, connection resets, HTTP 429, and 5xx responses) are retried
+per Client.MaxRetries / MinRetryDelay / MaxRetryDelay, or Client.Retryable if set.
+
+When auth is permissions.Firewall or permissions.Rulestack, the JWT is refreshed
+proactively when close to expiry and reactively on a 401/403, with concurrent
+refreshes for the same auth collapsed into a single AssumeRole + token exchange.
+
+This is a thin wrapper around CommunicateWithContext using context.Background();
+callers that need cancellation or deadlines should call CommunicateWithContext directly.
 */
 func (c *Client) Communicate(auth, method string, path []string, input interface{}, output api.Oker, creds ...*sts.Credentials) ([]byte, error) {
+	return c.CommunicateWithContext(context.Background(), auth, method, path, input, output, creds...)
+}
+
+// CommunicateWithContext is Communicate with caller-supplied context.Context
+// propagation and cancellation: the request is built with
+// http.NewRequestWithContext so ctx's deadline and cancellation are honored
+// for the duration of the call.
+func (c *Client) CommunicateWithContext(ctx context.Context, auth, method string, path []string, input interface{}, output api.Oker, creds ...*sts.Credentials) ([]byte, error) {
 	// Sanity check the input.
 	if len(creds) > 1 {
 		return nil, fmt.Errorf("Only one credentials is allowed")
@@ -231,57 +610,36 @@ func (c *Client) Communicate(auth, method string, path []string, input interface
 	if len(c.testData) > 0 {
 		body = []byte(`{"test"}`)
 	} else {
-		// Create the request.
-		req, err := http.NewRequest(
-			method,
-			fmt.Sprintf("%s/%s", c.apiPrefix, strings.Join(path, "/")),
-			strings.NewReader(string(data)),
-		)
-		if err != nil {
-			return nil, err
+		// Proactively refresh a JWT that's about to expire so a long-running
+		// caller doesn't have to hit a 401/403 first.
+		if auth == permissions.Firewall || auth == permissions.Rulestack {
+			if err = c.ensureJwt(ctx, auth); err != nil {
+				return nil, err
+			}
 		}
 
-		// Configure headers.
-		req.Header.Set("Content-Type", "application/json")
-		switch auth {
-		case "":
-		case permissions.Firewall:
-			req.Header.Set("Authorization", c.FirewallJwt)
-		case permissions.Rulestack:
-			req.Header.Set("Authorization", c.RulestackJwt)
-		default:
-			return nil, fmt.Errorf("Unknown auth type: %q", auth)
-		}
-		for k, v := range c.Headers {
-			req.Header.Set(k, v)
+		url := fmt.Sprintf("%s/%s", c.apiPrefix, strings.Join(path, "/"))
+
+		var statusCode int
+		statusCode, body, err = c.send(ctx, method, url, data, auth, creds...)
+		if err != nil {
+			return nil, err
 		}
 
-		// Optional: v4 sign the request.
-		if len(creds) == 1 {
-			prov := provider{
-				Value: credentials.Value{
-					AccessKeyID:     *creds[0].AccessKeyId,
-					SecretAccessKey: *creds[0].SecretAccessKey,
-					SessionToken:    *creds[0].SessionToken,
-				},
+		// The JWT expired server-side before our tracked expiry predicted
+		// it would: force a refresh of the affected admin type (our expiry
+		// estimate clearly can't be trusted here) and retry once.
+		if (statusCode == http.StatusUnauthorized || statusCode == http.StatusForbidden) &&
+			(auth == permissions.Firewall || auth == permissions.Rulestack) {
+			if err = c.refreshAuthJwt(ctx, auth, true); err != nil {
+				return nil, err
 			}
-			signer := v4.NewSigner(credentials.NewCredentials(prov))
-			_, err = signer.Sign(req, strings.NewReader(string(data)), "execute-api", c.Region, time.Now())
+
+			_, body, err = c.send(ctx, method, url, data, auth, creds...)
 			if err != nil {
 				return nil, err
 			}
 		}
-
-		// Perform the API action.
-		resp, err := c.con.Do(req)
-		if err != nil {
-			return nil, err
-		}
-		defer resp.Body.Close()
-		body, err = ioutil.ReadAll(resp.Body)
-		if err != nil {
-			return nil, err
-		}
 	}
 
 	if c.Logging&LogReceive == LogReceive {
@@ -306,6 +664,149 @@ func (c *Client) Communicate(auth, method string, path []string, input interface
 
 /* Internal functions. */
 
+// send performs one logical request to url, retrying transient failures per
+// Client.MaxRetries / MinRetryDelay / MaxRetryDelay (or Client.Retryable if
+// set), and returns the final HTTP status code alongside the response body.
+// The request (including its body reader and, if creds is given, its SigV4
+// signature) is rebuilt on every attempt.
+func (c *Client) send(ctx context.Context, method, url string, data []byte, auth string, creds ...*sts.Credentials) (int, []byte, error) {
+	retryable := c.Retryable
+	if retryable == nil {
+		retryable = defaultRetryable
+	}
+
+	for attempt := 0; ; attempt++ {
+		req, reqErr := http.NewRequestWithContext(ctx, method, url, strings.NewReader(string(data)))
+		if reqErr != nil {
+			return 0, nil, reqErr
+		}
+
+		req.Header.Set("Content-Type", "application/json")
+		switch auth {
+		case "":
+		case permissions.Firewall, permissions.Rulestack:
+			req.Header.Set("Authorization", c.getJwt(auth))
+		default:
+			return 0, nil, fmt.Errorf("Unknown auth type: %q", auth)
+		}
+		for k, v := range c.Headers {
+			req.Header.Set(k, v)
+		}
+
+		// Optional: v4 sign the request. Done per attempt since the
+		// signature covers the date header, which changes on retry.
+		if len(creds) == 1 {
+			if err := c.signV4(req, data, creds[0]); err != nil {
+				return 0, nil, err
+			}
+		}
+
+		// Perform the API action.
+		resp, doErr := c.con.Do(req)
+		var statusCode int
+		var respBody []byte
+		if doErr == nil {
+			statusCode = resp.StatusCode
+			respBody, doErr = ioutil.ReadAll(resp.Body)
+			resp.Body.Close()
+		}
+
+		if attempt >= c.MaxRetries || !retryable(resp, doErr) {
+			if doErr != nil {
+				return statusCode, nil, doErr
+			}
+			return statusCode, respBody, nil
+		}
+
+		delay := c.retryDelay(attempt, resp)
+		c.Log(method, "retrying %s (attempt %d/%d) after %s: %v", url, attempt+1, c.MaxRetries, delay, doErr)
+
+		select {
+		case <-ctx.Done():
+			return 0, nil, ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+// defaultRetryable is the default Retryable decision used when Client.Retryable
+// is nil: retry on net.Error timeouts, connection resets, HTTP 429, and 5xx
+// responses.
+func defaultRetryable(resp *http.Response, err error) bool {
+	if err != nil {
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			return true
+		}
+		return strings.Contains(err.Error(), "connection reset")
+	}
+
+	if resp == nil {
+		return false
+	}
+
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError
+}
+
+// retryDelay computes the backoff before the next attempt. It honors a
+// Retry-After header when present; otherwise it backs off exponentially
+// from MinRetryDelay, capped at MaxRetryDelay, plus uniform jitter in
+// [0, delay/2].
+func (c *Client) retryDelay(attempt int, resp *http.Response) time.Duration {
+	if resp != nil {
+		if ra := resp.Header.Get("Retry-After"); ra != "" {
+			if secs, err := strconv.Atoi(ra); err == nil {
+				return time.Duration(secs) * time.Second
+			}
+			if t, err := http.ParseTime(ra); err == nil {
+				if d := time.Until(t); d > 0 {
+					return d
+				}
+			}
+		}
+	}
+
+	delay := c.MinRetryDelay * time.Duration(int64(1)<<uint(attempt))
+	if delay <= 0 || delay > c.MaxRetryDelay {
+		delay = c.MaxRetryDelay
+	}
+
+	return delay + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
+
+// signV4 SigV4-signs req with the given STS credentials. When c was built
+// with NewClientV2, signing goes through aws-sdk-go-v2's signer/v4;
+// otherwise the legacy aws-sdk-go v1 signer is used.
+func (c *Client) signV4(req *http.Request, data []byte, creds *sts.Credentials) error {
+	if c.cfgV2 != nil {
+		payloadHash := fmt.Sprintf("%x", sha256.Sum256(data))
+		return signerv2.NewSigner().SignHTTP(
+			context.Background(),
+			awsv2.Credentials{
+				AccessKeyID:     *creds.AccessKeyId,
+				SecretAccessKey: *creds.SecretAccessKey,
+				SessionToken:    *creds.SessionToken,
+			},
+			req,
+			payloadHash,
+			"execute-api",
+			c.Region,
+			time.Now(),
+		)
+	}
+
+	prov := provider{
+		Value: credentials.Value{
+			AccessKeyID:     *creds.AccessKeyId,
+			SecretAccessKey: *creds.SecretAccessKey,
+			SessionToken:    *creds.SessionToken,
+		},
+	}
+	signer := v4.NewSigner(credentials.NewCredentials(prov))
+	_, err := signer.Sign(req, strings.NewReader(string(data)), "execute-api", c.Region, time.Now())
+	return err
+}
+
 func (c *Client) initCon() error {
 	var err error
 	var tout time.Duration
@@ -395,6 +896,24 @@ func (c *Client) initCon() error {
 		}
 	}
 
+	// Retries. MaxRetries is left untouched once it's anything other than 0
+	// (including the documented -1 "explicitly zero retries" sentinel) so
+	// that this block stays idempotent across repeated initCon calls, e.g.
+	// NewClientV2 calling it directly and then again via Initialize.
+	if c.MaxRetries == 0 {
+		if json_client.MaxRetries != 0 {
+			c.MaxRetries = json_client.MaxRetries
+		} else {
+			c.MaxRetries = 3
+		}
+	}
+	if c.MinRetryDelay == 0 {
+		c.MinRetryDelay = 50 * time.Millisecond
+	}
+	if c.MaxRetryDelay == 0 {
+		c.MaxRetryDelay = 5 * time.Second
+	}
+
 	// Verify cert.
 	if !c.SkipVerifyCertificate {
 		if val := os.Getenv("CLOUD_NGFW_VERIFY_CERTIFICATE"); c.CheckEnvironment && val != "" {
@@ -0,0 +1,75 @@
+package cloudngfw
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/paloaltonetworks/cloud-ngfw-aws-go/permissions"
+)
+
+func TestRetryDelayExponentialBackoff(t *testing.T) {
+	c := &Client{MinRetryDelay: 50 * time.Millisecond, MaxRetryDelay: 5 * time.Second}
+
+	cases := []struct {
+		attempt int
+		base    time.Duration
+	}{
+		{0, 50 * time.Millisecond},
+		{1, 100 * time.Millisecond},
+		{2, 200 * time.Millisecond},
+	}
+	for _, tc := range cases {
+		delay := c.retryDelay(tc.attempt, nil)
+		if delay < tc.base || delay > tc.base+tc.base/2 {
+			t.Errorf("attempt %d: delay %s outside [%s, %s]", tc.attempt, delay, tc.base, tc.base+tc.base/2)
+		}
+	}
+}
+
+func TestRetryDelayCapsAtMaxRetryDelay(t *testing.T) {
+	c := &Client{MinRetryDelay: 50 * time.Millisecond, MaxRetryDelay: 1 * time.Second}
+
+	// 50ms * 2^10 would blow well past MaxRetryDelay without the cap.
+	delay := c.retryDelay(10, nil)
+	if delay < c.MaxRetryDelay || delay > c.MaxRetryDelay+c.MaxRetryDelay/2 {
+		t.Errorf("delay %s not capped at MaxRetryDelay plus jitter", delay)
+	}
+}
+
+func TestRetryDelayHonorsRetryAfterSeconds(t *testing.T) {
+	c := &Client{MinRetryDelay: 50 * time.Millisecond, MaxRetryDelay: 5 * time.Second}
+	resp := &http.Response{Header: http.Header{"Retry-After": []string{"2"}}}
+
+	if delay := c.retryDelay(0, resp); delay != 2*time.Second {
+		t.Errorf("expected Retry-After to be honored exactly, got %s", delay)
+	}
+}
+
+// TestRefreshAuthJwtForceBypassesFreshnessCheck exercises the singleflight
+// callback in refreshAuthJwt without a real AssumeRole call: a fresh JWT
+// leaves jwtNeedsRefresh false, so a non-forced refresh should no-op, while a
+// forced refresh must still call through to refreshJwtFor regardless -- which
+// fails fast here with a recognizable error since no role ARN is configured,
+// proving the freshness check was bypassed rather than silently skipped.
+func TestRefreshAuthJwtForceBypassesFreshnessCheck(t *testing.T) {
+	c := &Client{}
+	c.setJwt(permissions.Firewall, "still-valid")
+
+	if err := c.refreshAuthJwt(context.Background(), permissions.Firewall, false); err != nil {
+		t.Fatalf("unexpected error on non-forced refresh of a fresh JWT: %v", err)
+	}
+	if jwt := c.getJwt(permissions.Firewall); jwt != "still-valid" {
+		t.Fatalf("non-forced refresh of a fresh JWT should leave it untouched, got %q", jwt)
+	}
+
+	err := c.refreshAuthJwt(context.Background(), permissions.Firewall, true)
+	if err == nil {
+		t.Fatal("expected forced refresh to call through to refreshJwtFor and fail without a configured role ARN")
+	}
+	if !strings.Contains(err.Error(), "No role ARN configured") {
+		t.Fatalf("unexpected error from forced refresh: %v", err)
+	}
+}